@@ -0,0 +1,20 @@
+// Package certificateutil handles reading code signing identities
+// (certificate + private key pairs) exported from the macOS Keychain.
+package certificateutil
+
+import "time"
+
+// CertificateInfoModel is the parsed representation of a code signing
+// identity, as exported into a temporary .p12 file for codesigndoc to pick
+// up and re-export.
+type CertificateInfoModel struct {
+	Path string
+
+	CommonName        string
+	TeamName          string
+	SerialNumber      string
+	SHA1Fingerprint   string
+	SHA256Fingerprint string
+	NotBefore         time.Time
+	NotAfter          time.Time
+}
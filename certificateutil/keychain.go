@@ -0,0 +1,103 @@
+package certificateutil
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/cmdex"
+	"github.com/bitrise-io/go-utils/pathutil"
+)
+
+const loginKeychainName = "login.keychain"
+
+// InstalledCertificateInfos lists every code signing certificate currently
+// present in the login Keychain.
+func InstalledCertificateInfos() ([]CertificateInfoModel, error) {
+	out, err := cmdex.RunCommandAndReturnCombinedStdoutAndStderr("security", "find-certificate", "-a", "-p", loginKeychainName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Keychain certificates: %s: %s", err, out)
+	}
+	return parseCertificateInfos([]byte(out))
+}
+
+func parseCertificateInfos(pemBlocks []byte) ([]CertificateInfoModel, error) {
+	var certInfos []CertificateInfoModel
+
+	rest := pemBlocks
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse certificate: %s", err)
+		}
+		certInfos = append(certInfos, certificateInfoFromX509(cert))
+	}
+
+	return certInfos, nil
+}
+
+func certificateInfoFromX509(cert *x509.Certificate) CertificateInfoModel {
+	teamName := ""
+	if len(cert.Subject.OrganizationalUnit) > 0 {
+		teamName = cert.Subject.OrganizationalUnit[0]
+	}
+
+	return CertificateInfoModel{
+		CommonName:        cert.Subject.CommonName,
+		TeamName:          teamName,
+		SerialNumber:      cert.SerialNumber.String(),
+		SHA1Fingerprint:   fingerprintHex(cert.Raw, sha1Sum),
+		SHA256Fingerprint: fingerprintHex(cert.Raw, sha256Sum),
+		NotBefore:         cert.NotBefore,
+		NotAfter:          cert.NotAfter,
+	}
+}
+
+func sha1Sum(data []byte) []byte {
+	sum := sha1.Sum(data)
+	return sum[:]
+}
+
+func sha256Sum(data []byte) []byte {
+	sum := sha256.Sum256(data)
+	return sum[:]
+}
+
+func fingerprintHex(data []byte, hashFn func([]byte) []byte) string {
+	return strings.ToUpper(hex.EncodeToString(hashFn(data)))
+}
+
+// ExportWithPrivateKey exports certInfo's identity (certificate + private
+// key) from the login Keychain into a temporary .p12 file protected by
+// password, and returns an updated CertificateInfoModel with Path set to it.
+func (certInfo CertificateInfoModel) ExportWithPrivateKey(password string) (CertificateInfoModel, error) {
+	exportDirPath, err := pathutil.NormalizedOSTempDirPath("codesigndoc-cert-export")
+	if err != nil {
+		return CertificateInfoModel{}, fmt.Errorf("failed to create temp dir for Certificate export: %s", err)
+	}
+	exportPath := filepath.Join(exportDirPath, certInfo.SHA1Fingerprint+".p12")
+
+	if err := cmdex.RunCommand("security", "export",
+		"-k", loginKeychainName,
+		"-t", "identities",
+		"-f", "pkcs12",
+		"-P", password,
+		"-o", exportPath,
+		certInfo.CommonName); err != nil {
+		return CertificateInfoModel{}, fmt.Errorf("security export failed for identity %s: %s", certInfo.CommonName, err)
+	}
+
+	certInfo.Path = exportPath
+	return certInfo, nil
+}
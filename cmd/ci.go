@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// isCIEnvironmentEnabled reports whether codesigndoc should auto-enable
+// non-interactive --ci mode, mirroring how other Bitrise tools detect CI:
+// either the Bitrise-specific or the generic env var being set to "true".
+func isCIEnvironmentEnabled() bool {
+	return os.Getenv("BITRISE_CI") == "true" || os.Getenv("CI") == "true"
+}
+
+// requireCIFlag fails fast with an actionable message if a flag codesigndoc
+// needs in --ci mode was left empty, instead of falling through to a
+// goinp prompt that would hang forever with no stdin attached.
+func requireCIFlag(value, flagName, description string) error {
+	if value != "" {
+		return nil
+	}
+	return fmt.Errorf("--ci mode requires %s (%s) to be set, no interactive prompts are shown in CI mode", flagName, description)
+}
+
+// resolveExportOutputDir returns the absolute export directory to use:
+// explicitDirPath if given (created if it doesn't exist yet), or the
+// interactively-selected default otherwise.
+func resolveExportOutputDir(explicitDirPath string) (string, error) {
+	if explicitDirPath == "" {
+		return initExportOutputDir()
+	}
+
+	absDirPath, err := filepath.Abs(explicitDirPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to determine absolute path for export-output-dir (%s): %s", explicitDirPath, err)
+	}
+	if err := os.MkdirAll(absDirPath, 0755); err != nil {
+		return "", fmt.Errorf("failed to create export-output-dir (%s): %s", absDirPath, err)
+	}
+	return absDirPath, nil
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIsCIEnvironmentEnabled(t *testing.T) {
+	tests := []struct {
+		name      string
+		bitriseCI string
+		ci        string
+		want      bool
+	}{
+		{name: "neither set", want: false},
+		{name: "BITRISE_CI=true", bitriseCI: "true", want: true},
+		{name: "CI=true", ci: "true", want: true},
+		{name: "CI=false", ci: "false", want: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			defer os.Unsetenv("BITRISE_CI")
+			defer os.Unsetenv("CI")
+			os.Unsetenv("BITRISE_CI")
+			os.Unsetenv("CI")
+			if test.bitriseCI != "" {
+				os.Setenv("BITRISE_CI", test.bitriseCI)
+			}
+			if test.ci != "" {
+				os.Setenv("CI", test.ci)
+			}
+
+			if got := isCIEnvironmentEnabled(); got != test.want {
+				t.Errorf("isCIEnvironmentEnabled() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRequireCIFlag(t *testing.T) {
+	if err := requireCIFlag("some-value", "--flag", "description"); err != nil {
+		t.Errorf("unexpected error for a non-empty value: %s", err)
+	}
+
+	if err := requireCIFlag("", "--flag", "description"); err == nil {
+		t.Error("expected an error for an empty value, got nil")
+	}
+}
+
+func TestResolveExportOutputDir_Explicit(t *testing.T) {
+	baseDir, err := ioutil.TempDir("", "codesigndoc-ci-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(baseDir)
+
+	explicitDir := filepath.Join(baseDir, "export-output")
+	got, err := resolveExportOutputDir(explicitDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got != explicitDir {
+		t.Errorf("resolveExportOutputDir() = %q, want %q", got, explicitDir)
+	}
+	if info, err := os.Stat(explicitDir); err != nil || !info.IsDir() {
+		t.Errorf("expected %q to be created as a directory", explicitDir)
+	}
+}
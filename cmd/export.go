@@ -0,0 +1,203 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/bitrise-io/go-utils/cmdex"
+	"github.com/bitrise-io/go-utils/colorstring"
+	"github.com/bitrise-io/go-utils/fileutil"
+	"github.com/bitrise-tools/codesigndoc/certificateutil"
+	"github.com/bitrise-tools/codesigndoc/models"
+	"github.com/bitrise-tools/codesigndoc/provprofile"
+)
+
+const (
+	defaultExportOutputDirName = "./codesigndoc_exports"
+	exportManifestFileName     = "codesigndoc-export.json"
+)
+
+// scanRunInfo captures the non-code-signing context of a single scan run,
+// so it can be recorded alongside the exported assets in the JSON manifest.
+type scanRunInfo struct {
+	ProjectPath     string
+	Scheme          string
+	Configuration   string
+	ArchiveBundleID string
+	BuildLogPath    string
+}
+
+// exportManifest is the structure written to codesigndoc-export.json,
+// describing every asset codesigndoc copied into the export dir.
+type exportManifest struct {
+	Certificates         []exportManifestCertificate         `json:"certificates"`
+	ProvisioningProfiles []exportManifestProvisioningProfile `json:"provisioning_profiles"`
+	Build                exportManifestBuild                 `json:"build"`
+}
+
+type exportManifestCertificate struct {
+	CommonName        string    `json:"common_name"`
+	Team              string    `json:"team"`
+	SerialNumber      string    `json:"serial_number"`
+	SHA1Fingerprint   string    `json:"sha1_fingerprint"`
+	SHA256Fingerprint string    `json:"sha256_fingerprint"`
+	NotBefore         time.Time `json:"not_before"`
+	NotAfter          time.Time `json:"not_after"`
+}
+
+type exportManifestProvisioningProfile struct {
+	UUID                    string                 `json:"uuid"`
+	Name                    string                 `json:"name"`
+	BundleID                string                 `json:"bundle_id"`
+	Entitlements            map[string]interface{} `json:"entitlements"`
+	DistributionType        string                 `json:"distribution_type"`
+	ExpirationDate          time.Time              `json:"expiration_date"`
+	CertificateFingerprints []string               `json:"certificate_sha1_fingerprints"`
+}
+
+type exportManifestBuild struct {
+	ProjectPath     string `json:"project_path,omitempty"`
+	Scheme          string `json:"scheme,omitempty"`
+	Configuration   string `json:"configuration,omitempty"`
+	ArchiveBundleID string `json:"archive_bundle_id,omitempty"`
+	LogPath         string `json:"log_path,omitempty"`
+}
+
+func initExportOutputDir() (string, error) {
+	absExportOutputDirPath, err := filepath.Abs(defaultExportOutputDirName)
+	if err != nil {
+		return "", fmt.Errorf("Failed to determine absolute path for export dir (%s): %s", defaultExportOutputDirName, err)
+	}
+	if err := os.MkdirAll(absExportOutputDirPath, 0755); err != nil {
+		return "", fmt.Errorf("Failed to create export dir (%s): %s", absExportOutputDirPath, err)
+	}
+	return absExportOutputDirPath, nil
+}
+
+func printFinishedWithError(toolName string, format string, args ...interface{}) error {
+	errMsg := fmt.Sprintf(format, args...)
+	log.Errorln(colorstring.Red(toolName + " scan finished with error:"))
+	log.Errorln(errMsg)
+	return fmt.Errorf("%s scan finished with error: %s", toolName, errMsg)
+}
+
+func exportCertificates(certInfos []certificateutil.CertificateInfoModel, exportTargetDirPath string) error {
+	for _, certInfo := range certInfos {
+		log.Infoln("   " + colorstring.Green("Exporting Certificate:") + " " + certInfo.CommonName)
+		exportFileName := certInfo.SHA1Fingerprint + ".p12"
+		exportPth := filepath.Join(exportTargetDirPath, exportFileName)
+		if err := cmdex.RunCommand("cp", certInfo.Path, exportPth); err != nil {
+			return fmt.Errorf("Failed to copy Certificate (from: %s) (to: %s), error: %s",
+				certInfo.Path, exportPth, err)
+		}
+	}
+	return nil
+}
+
+// exportCodeSigningFiles copies every Certificate and Provisioning Profile
+// in codeSigningSettings into absExportOutputDirPath, and writes a
+// codesigndoc-export.json manifest describing them.
+func exportCodeSigningFiles(toolName string, absExportOutputDirPath string,
+	codeSigningSettings models.CodeSigningSettings, runInfo scanRunInfo) error {
+
+	fmt.Println()
+	log.Infoln(colorstring.Green("Exporting the required code signing files..."))
+
+	if err := exportCertificates(codeSigningSettings.Certificates, absExportOutputDirPath); err != nil {
+		return fmt.Errorf("Failed to export Certificates: %s", err)
+	}
+
+	exportedProvisioningProfiles, err := exportProvisioningProfiles(codeSigningSettings.ProvisioningProfiles, absExportOutputDirPath)
+	if err != nil {
+		return fmt.Errorf("Failed to export Provisioning Profiles: %s", err)
+	}
+
+	if err := writeExportManifest(codeSigningSettings, exportedProvisioningProfiles, absExportOutputDirPath, runInfo); err != nil {
+		return fmt.Errorf("Failed to write export manifest: %s", err)
+	}
+
+	fmt.Println()
+	log.Infoln(colorstring.Green("Done"))
+	log.Infoln("You can find the exported files at: " + absExportOutputDirPath)
+	return nil
+}
+
+func writeExportManifest(codeSigningSettings models.CodeSigningSettings,
+	exportedProvisioningProfiles []provprofile.ProvisioningProfileFileInfoModel, exportDirPath string, runInfo scanRunInfo) error {
+
+	manifest := exportManifest{
+		Certificates:         manifestCertificates(codeSigningSettings.Certificates),
+		ProvisioningProfiles: manifestProvisioningProfiles(exportedProvisioningProfiles),
+		Build:                manifestBuild(runInfo),
+	}
+
+	manifestBytes, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal export manifest: %s", err)
+	}
+
+	manifestPth := filepath.Join(exportDirPath, exportManifestFileName)
+	if err := fileutil.WriteBytesToFile(manifestPth, manifestBytes); err != nil {
+		return fmt.Errorf("failed to write export manifest (%s): %s", manifestPth, err)
+	}
+	log.Infoln("   " + colorstring.Green("Wrote export manifest:") + " " + manifestPth)
+	return nil
+}
+
+func manifestCertificates(certs []certificateutil.CertificateInfoModel) []exportManifestCertificate {
+	manifestCerts := make([]exportManifestCertificate, 0, len(certs))
+	for _, cert := range certs {
+		manifestCerts = append(manifestCerts, exportManifestCertificate{
+			CommonName:        cert.CommonName,
+			Team:              cert.TeamName,
+			SerialNumber:      cert.SerialNumber,
+			SHA1Fingerprint:   cert.SHA1Fingerprint,
+			SHA256Fingerprint: cert.SHA256Fingerprint,
+			NotBefore:         cert.NotBefore,
+			NotAfter:          cert.NotAfter,
+		})
+	}
+	return manifestCerts
+}
+
+func manifestProvisioningProfiles(profileInfos []provprofile.ProvisioningProfileFileInfoModel) []exportManifestProvisioningProfile {
+	manifestProfiles := make([]exportManifestProvisioningProfile, 0, len(profileInfos))
+	for _, profileInfo := range profileInfos {
+		info := profileInfo.ProvisioningProfileInfo
+
+		certFingerprints := make([]string, 0, len(info.DeveloperCertificates))
+		for _, cert := range info.DeveloperCertificates {
+			certFingerprints = append(certFingerprints, cert.SHA1Fingerprint)
+		}
+
+		manifestProfiles = append(manifestProfiles, exportManifestProvisioningProfile{
+			UUID:                    info.UUID,
+			Name:                    info.Name,
+			BundleID:                info.BundleIdentifier(),
+			Entitlements:            info.Entitlements,
+			DistributionType:        info.DistributionType(),
+			ExpirationDate:          info.ExpirationDate,
+			CertificateFingerprints: certFingerprints,
+		})
+	}
+	return manifestProfiles
+}
+
+// manifestBuild reports runInfo.ArchiveBundleID as-is, leaving it empty if
+// the caller didn't set it - guessing it from whichever Provisioning
+// Profile happened to come first would be wrong for any Archive that
+// signs more than one target (e.g. an app plus an extension or watch app).
+func manifestBuild(runInfo scanRunInfo) exportManifestBuild {
+	return exportManifestBuild{
+		ProjectPath:     runInfo.ProjectPath,
+		Scheme:          runInfo.Scheme,
+		Configuration:   runInfo.Configuration,
+		ArchiveBundleID: runInfo.ArchiveBundleID,
+		LogPath:         runInfo.BuildLogPath,
+	}
+}
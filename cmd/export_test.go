@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bitrise-tools/codesigndoc/certificateutil"
+	"github.com/bitrise-tools/codesigndoc/provprofile"
+)
+
+func TestManifestCertificates(t *testing.T) {
+	now := time.Now()
+	certs := []certificateutil.CertificateInfoModel{
+		{
+			CommonName:        "iPhone Distribution: Test Team",
+			TeamName:          "Test Team",
+			SerialNumber:      "1234",
+			SHA1Fingerprint:   "AABBCC",
+			SHA256Fingerprint: "AABBCCDDEE",
+			NotBefore:         now,
+			NotAfter:          now.Add(time.Hour),
+		},
+	}
+
+	got := manifestCertificates(certs)
+	if len(got) != 1 {
+		t.Fatalf("expected 1 manifest certificate, got %d", len(got))
+	}
+	if got[0].CommonName != certs[0].CommonName || got[0].SHA1Fingerprint != certs[0].SHA1Fingerprint {
+		t.Errorf("manifestCertificates()[0] = %+v, want fields copied from %+v", got[0], certs[0])
+	}
+}
+
+func TestManifestProvisioningProfiles(t *testing.T) {
+	profiles := []provprofile.ProvisioningProfileFileInfoModel{
+		{
+			Path: "/tmp/profile.mobileprovision",
+			ProvisioningProfileInfo: provprofile.ProvisioningProfileInfoModel{
+				UUID:   "uuid-1",
+				Name:   "Test Profile",
+				TeamID: "TEAM1",
+				Entitlements: map[string]interface{}{
+					"application-identifier": "TEAM1.com.example.app",
+				},
+				DeveloperCertificates: []provprofile.DeveloperCertificateModel{
+					{SHA1Fingerprint: "AABBCC"},
+				},
+			},
+		},
+	}
+
+	got := manifestProvisioningProfiles(profiles)
+	if len(got) != 1 {
+		t.Fatalf("expected 1 manifest Provisioning Profile, got %d", len(got))
+	}
+	if got[0].UUID != "uuid-1" {
+		t.Errorf("UUID = %q, want %q", got[0].UUID, "uuid-1")
+	}
+	if got[0].BundleID != "com.example.app" {
+		t.Errorf("BundleID = %q, want %q", got[0].BundleID, "com.example.app")
+	}
+	if len(got[0].CertificateFingerprints) != 1 || got[0].CertificateFingerprints[0] != "AABBCC" {
+		t.Errorf("CertificateFingerprints = %v, want [AABBCC]", got[0].CertificateFingerprints)
+	}
+}
+
+func TestManifestBuild(t *testing.T) {
+	runInfo := scanRunInfo{
+		ProjectPath:   "/path/to/project.xcodeproj",
+		Scheme:        "MyScheme",
+		Configuration: "Release",
+		BuildLogPath:  "/path/to/log",
+	}
+
+	got := manifestBuild(runInfo)
+	if got.ArchiveBundleID != "" {
+		t.Errorf("ArchiveBundleID = %q, want empty when runInfo didn't set it - it must not be guessed from a Provisioning Profile", got.ArchiveBundleID)
+	}
+	if got.ProjectPath != runInfo.ProjectPath || got.Scheme != runInfo.Scheme || got.Configuration != runInfo.Configuration || got.LogPath != runInfo.BuildLogPath {
+		t.Errorf("manifestBuild() = %+v, want fields copied from %+v", got, runInfo)
+	}
+}
+
+func TestManifestBuild_PassesThroughExplicitArchiveBundleID(t *testing.T) {
+	runInfo := scanRunInfo{ArchiveBundleID: "com.example.app"}
+
+	if got := manifestBuild(runInfo).ArchiveBundleID; got != "com.example.app" {
+		t.Errorf("ArchiveBundleID = %q, want %q", got, "com.example.app")
+	}
+}
@@ -0,0 +1,275 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/bitrise-io/go-utils/colorstring"
+	"github.com/spf13/cobra"
+)
+
+// uploadCmd represents the upload command
+var uploadCmd = &cobra.Command{
+	Use:   "upload",
+	Short: "Upload exported code signing files to bitrise.io",
+	Long:  `Upload the Certificates and Provisioning Profiles of a codesigndoc export directory to a Bitrise app`,
+
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE:          uploadExportedCodeSigningFiles,
+}
+
+var (
+	paramUploadExportDir = ""
+	paramUploadAppSlug   = ""
+	paramUploadDryRun    = false
+)
+
+func init() {
+	rootCmd.AddCommand(uploadCmd)
+
+	uploadCmd.Flags().StringVar(&paramUploadExportDir, "export-dir", "",
+		"Path to a codesigndoc export directory (the one containing codesigndoc-export.json)")
+	uploadCmd.Flags().StringVar(&paramUploadAppSlug, "app-slug", "",
+		"Bitrise App slug to upload the Certificates and Provisioning Profiles to")
+	uploadCmd.Flags().BoolVar(&paramUploadDryRun, "dry-run", false,
+		"Print what would be uploaded, without calling the Bitrise API")
+}
+
+const bitriseAPIBaseURL = "https://api.bitrise.io/v0.1"
+
+func printUploadFinishedWithError(format string, args ...interface{}) error {
+	return printFinishedWithError("Upload", format, args...)
+}
+
+func uploadExportedCodeSigningFiles(cmd *cobra.Command, args []string) error {
+	if err := requireCIFlag(paramUploadExportDir, "--export-dir", "a directory produced by a previous codesigndoc scan"); err != nil {
+		return printUploadFinishedWithError("%s", err)
+	}
+	return performUpload(paramUploadExportDir, paramUploadAppSlug, paramUploadDryRun)
+}
+
+// performUpload uploads the Certificates and Provisioning Profiles
+// described by exportDirPath's codesigndoc-export.json manifest to the
+// Bitrise app identified by appSlug, skipping assets it already has.
+func performUpload(exportDirPath, appSlug string, dryRun bool) error {
+	if err := requireCIFlag(appSlug, "--app-slug", "the Bitrise App slug to upload to"); err != nil {
+		return printUploadFinishedWithError("%s", err)
+	}
+
+	accessToken := os.Getenv("BITRISE_ACCESS_TOKEN")
+	if accessToken == "" && !dryRun {
+		return printUploadFinishedWithError("BITRISE_ACCESS_TOKEN is not set")
+	}
+
+	manifest, err := readExportManifest(exportDirPath)
+	if err != nil {
+		return printUploadFinishedWithError("Failed to read export manifest: %s", err)
+	}
+
+	client := bitriseUploadClient{
+		baseURL:     bitriseAPIBaseURL,
+		accessToken: accessToken,
+		appSlug:     appSlug,
+		dryRun:      dryRun,
+	}
+
+	existingCertFingerprints, existingProfileUUIDs, err := client.existingAssetIdentifiers()
+	if err != nil {
+		return printUploadFinishedWithError("Failed to list existing code signing files on Bitrise: %s", err)
+	}
+
+	failed := false
+
+	for _, cert := range manifest.Certificates {
+		if existingCertFingerprints[cert.SHA1Fingerprint] {
+			log.Infoln("   " + colorstring.Yellow("Skipping Certificate (already on Bitrise):") + " " + cert.CommonName)
+			continue
+		}
+		certPath := filepath.Join(exportDirPath, cert.SHA1Fingerprint+".p12")
+		if err := client.uploadCertificate(certPath, cert); err != nil {
+			log.Errorln("   " + colorstring.Red("Failed to upload Certificate:") + " " + cert.CommonName + ": " + err.Error())
+			failed = true
+			continue
+		}
+		log.Infoln("   " + colorstring.Green("Uploaded Certificate:") + " " + cert.CommonName)
+	}
+
+	for _, profile := range manifest.ProvisioningProfiles {
+		if existingProfileUUIDs[profile.UUID] {
+			log.Infoln("   " + colorstring.Yellow("Skipping Provisioning Profile (already on Bitrise):") + " " + profile.Name)
+			continue
+		}
+		profilePath, err := findProvisioningProfileExport(exportDirPath, profile.UUID)
+		if err != nil {
+			log.Errorln("   " + colorstring.Red("Failed to upload Provisioning Profile:") + " " + profile.Name + ": " + err.Error())
+			failed = true
+			continue
+		}
+		if err := client.uploadProvisioningProfile(profilePath, profile); err != nil {
+			log.Errorln("   " + colorstring.Red("Failed to upload Provisioning Profile:") + " " + profile.Name + ": " + err.Error())
+			failed = true
+			continue
+		}
+		log.Infoln("   " + colorstring.Green("Uploaded Provisioning Profile:") + " " + profile.Name)
+	}
+
+	if failed {
+		return printUploadFinishedWithError("One or more assets failed to upload, see the log above")
+	}
+	return nil
+}
+
+func readExportManifest(exportDirPath string) (exportManifest, error) {
+	manifestPth := filepath.Join(exportDirPath, exportManifestFileName)
+	manifestBytes, err := ioutil.ReadFile(manifestPth)
+	if err != nil {
+		return exportManifest{}, fmt.Errorf("failed to read %s: %s", manifestPth, err)
+	}
+
+	var manifest exportManifest
+	if err := json.Unmarshal(manifestBytes, &manifest); err != nil {
+		return exportManifest{}, fmt.Errorf("failed to parse %s: %s", manifestPth, err)
+	}
+	return manifest, nil
+}
+
+func findProvisioningProfileExport(exportDirPath, uuid string) (string, error) {
+	entries, err := ioutil.ReadDir(exportDirPath)
+	if err != nil {
+		return "", err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if filepath.Ext(entry.Name()) == ".mobileprovision" || filepath.Ext(entry.Name()) == ".provisionprofile" {
+			if len(entry.Name()) > len(uuid) && entry.Name()[:len(uuid)] == uuid {
+				return filepath.Join(exportDirPath, entry.Name()), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no exported Provisioning Profile file found for UUID %s in %s", uuid, exportDirPath)
+}
+
+// bitriseUploadClient talks to the Bitrise App code signing endpoints to
+// list what's already registered on an app and upload what's missing.
+type bitriseUploadClient struct {
+	baseURL     string
+	accessToken string
+	appSlug     string
+	dryRun      bool
+}
+
+type bitriseCertificateListItem struct {
+	SHA1Fingerprint string `json:"sha1_fingerprint"`
+}
+
+type bitriseProvisioningProfileListItem struct {
+	UUID string `json:"uuid"`
+}
+
+func (c bitriseUploadClient) existingAssetIdentifiers() (map[string]bool, map[string]bool, error) {
+	certFingerprints := map[string]bool{}
+	profileUUIDs := map[string]bool{}
+
+	if c.dryRun {
+		return certFingerprints, profileUUIDs, nil
+	}
+
+	var certs []bitriseCertificateListItem
+	if err := c.getJSON("/apps/"+c.appSlug+"/build-certificates", &certs); err != nil {
+		return nil, nil, err
+	}
+	for _, cert := range certs {
+		certFingerprints[cert.SHA1Fingerprint] = true
+	}
+
+	var profiles []bitriseProvisioningProfileListItem
+	if err := c.getJSON("/apps/"+c.appSlug+"/provisioning-profiles", &profiles); err != nil {
+		return nil, nil, err
+	}
+	for _, profile := range profiles {
+		profileUUIDs[profile.UUID] = true
+	}
+
+	return certFingerprints, profileUUIDs, nil
+}
+
+func (c bitriseUploadClient) uploadCertificate(path string, cert exportManifestCertificate) error {
+	if c.dryRun {
+		log.Infoln("   " + colorstring.Yellow("[dry-run] would upload Certificate:") + " " + path)
+		return nil
+	}
+	return c.uploadAsset("/apps/"+c.appSlug+"/build-certificates", path)
+}
+
+func (c bitriseUploadClient) uploadProvisioningProfile(path string, profile exportManifestProvisioningProfile) error {
+	if c.dryRun {
+		log.Infoln("   " + colorstring.Yellow("[dry-run] would upload Provisioning Profile:") + " " + path)
+		return nil
+	}
+	return c.uploadAsset("/apps/"+c.appSlug+"/provisioning-profiles", path)
+}
+
+func (c bitriseUploadClient) uploadAsset(relPath, filePath string) error {
+	fileBytes, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %s", filePath, err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.baseURL+relPath, bytes.NewReader(fileBytes))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "token "+c.accessToken)
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %s", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Warnf("Failed to close response body: %s", err)
+		}
+	}()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("Bitrise API returned %s: %s", resp.Status, string(body))
+	}
+	return nil
+}
+
+func (c bitriseUploadClient) getJSON(relPath string, out interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, c.baseURL+relPath, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "token "+c.accessToken)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %s", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Warnf("Failed to close response body: %s", err)
+		}
+	}()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("Bitrise API returned %s: %s", resp.Status, string(body))
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindProvisioningProfileExport(t *testing.T) {
+	exportDir, err := ioutil.TempDir("", "codesigndoc-upload-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(exportDir)
+
+	uuid := "12345678-1234-1234-1234-123456789abc"
+	wantPath := filepath.Join(exportDir, uuid+".Some Profile.mobileprovision")
+	if err := ioutil.WriteFile(wantPath, []byte("profile"), 0644); err != nil {
+		t.Fatalf("failed to write fixture file: %s", err)
+	}
+	// an unrelated file sharing no UUID prefix must not match
+	if err := ioutil.WriteFile(filepath.Join(exportDir, "codesigndoc-export.json"), []byte("{}"), 0644); err != nil {
+		t.Fatalf("failed to write fixture file: %s", err)
+	}
+
+	got, err := findProvisioningProfileExport(exportDir, uuid)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got != wantPath {
+		t.Errorf("findProvisioningProfileExport() = %q, want %q", got, wantPath)
+	}
+}
+
+func TestFindProvisioningProfileExport_NotFound(t *testing.T) {
+	exportDir, err := ioutil.TempDir("", "codesigndoc-upload-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(exportDir)
+
+	if _, err := findProvisioningProfileExport(exportDir, "missing-uuid"); err == nil {
+		t.Error("expected an error when no matching file exists, got nil")
+	}
+}
+
+func TestFindProvisioningProfileExport_IgnoresNonProfileExtensions(t *testing.T) {
+	exportDir, err := ioutil.TempDir("", "codesigndoc-upload-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(exportDir)
+
+	uuid := "12345678-1234-1234-1234-123456789abc"
+	if err := ioutil.WriteFile(filepath.Join(exportDir, uuid+".log"), []byte("not a profile"), 0644); err != nil {
+		t.Fatalf("failed to write fixture file: %s", err)
+	}
+
+	if _, err := findProvisioningProfileExport(exportDir, uuid); err == nil {
+		t.Error("expected an error when only a non-profile file matches the UUID prefix, got nil")
+	}
+}
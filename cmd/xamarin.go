@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/bitrise-io/go-utils/colorstring"
+	"github.com/bitrise-io/go-utils/fileutil"
+	"github.com/bitrise-io/goinp/goinp"
+	"github.com/bitrise-tools/codesigndoc/xamarin"
+	"github.com/spf13/cobra"
+)
+
+// xamarinCmd represents the xamarin command
+var xamarinCmd = &cobra.Command{
+	Use:   "xamarin",
+	Short: "Xamarin project scanner",
+	Long:  `Scan a Xamarin Solution`,
+
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE:          scanXamarinProject,
+}
+
+var (
+	paramXamarinSolutionFilePath = ""
+	paramXamarinProject          = ""
+	paramXamarinConfiguration    = ""
+	paramXamarinCI               = false
+	paramXamarinExportOutputDir  = ""
+	paramXamarinP12Password      = ""
+)
+
+func init() {
+	scanCmd.AddCommand(xamarinCmd)
+
+	xamarinCmd.Flags().StringVar(&paramXamarinSolutionFilePath,
+		"file", "",
+		"Xamarin Solution (.sln) file path")
+	xamarinCmd.Flags().StringVar(&paramXamarinProject,
+		"scheme", "",
+		"Xamarin iOS Project to build, in the form of \"ProjectName|Configuration|Platform\"")
+	xamarinCmd.Flags().StringVar(&paramXamarinConfiguration,
+		"configuration", "",
+		"Build Configuration to use (e.g. Release|iPhone); defaults to the selected Project's Archive configuration")
+	xamarinCmd.Flags().BoolVar(&paramXamarinCI,
+		"ci", false,
+		"Run non-interactively: disable every prompt and require --file, --scheme and --export-output-dir to be set. Auto-enabled when BITRISE_CI or CI is \"true\"")
+	xamarinCmd.Flags().StringVar(&paramXamarinExportOutputDir,
+		"export-output-dir", "",
+		"Directory to export the code signing files into (required in --ci mode)")
+	xamarinCmd.Flags().StringVar(&paramXamarinP12Password,
+		"p12-password", "",
+		"Password to use when exporting .p12 files (can also be set via CODESIGNDOC_P12_PASSWORD; not yet used by the Xamarin scanner)")
+}
+
+func printXamarinScanFinishedWithError(format string, args ...interface{}) error {
+	return printFinishedWithError("Xamarin", format, args...)
+}
+
+func scanXamarinProject(cmd *cobra.Command, args []string) error {
+	ciMode := paramXamarinCI || isCIEnvironmentEnabled()
+
+	// Unlike the Xcode scanner, this package doesn't export Keychain
+	// identities to a .p12 on its own, so --p12-password / CODESIGNDOC_P12_PASSWORD
+	// have nothing to feed into yet - warn instead of silently ignoring it.
+	if paramXamarinP12Password != "" || os.Getenv("CODESIGNDOC_P12_PASSWORD") != "" {
+		log.Warn(colorstring.Yellow("  --p12-password is set but the Xamarin scanner doesn't export Keychain identities yet - it will be ignored"))
+	}
+
+	if ciMode {
+		if err := requireCIFlag(paramXamarinSolutionFilePath, "--file", "path to the Xamarin Solution (.sln)"); err != nil {
+			return printXamarinScanFinishedWithError("%s", err)
+		}
+		if err := requireCIFlag(paramXamarinProject, "--scheme", "the Xamarin iOS Project to build"); err != nil {
+			return printXamarinScanFinishedWithError("%s", err)
+		}
+		if err := requireCIFlag(paramXamarinExportOutputDir, "--export-output-dir", "directory to export the code signing files into"); err != nil {
+			return printXamarinScanFinishedWithError("%s", err)
+		}
+	}
+
+	absExportOutputDirPath, err := resolveExportOutputDir(paramXamarinExportOutputDir)
+	if err != nil {
+		return printXamarinScanFinishedWithError("Failed to prepare Export directory: %s", err)
+	}
+
+	solutionPath := paramXamarinSolutionFilePath
+	if solutionPath == "" {
+		askText := `Please drag-and-drop your Xamarin Solution (` + colorstring.Green(".sln") + `) file here,
+   then hit Enter.`
+		fmt.Println()
+		projpth, err := goinp.AskForPath(askText)
+		if err != nil {
+			return printXamarinScanFinishedWithError("Failed to read input: %s", err)
+		}
+		solutionPath = projpth
+	}
+	log.Debugf("solutionPath: %s", solutionPath)
+	xamarinCmd := xamarin.CommandModel{
+		SolutionFilePath: solutionPath,
+		Configuration:    paramXamarinConfiguration,
+	}
+
+	projectToUse := paramXamarinProject
+	if projectToUse == "" {
+		fmt.Println()
+		fmt.Println()
+		log.Println("🔦  Scanning iOS Projects ...")
+		projects, err := xamarinCmd.ScanProjects()
+		if err != nil {
+			return printXamarinScanFinishedWithError("Failed to scan Projects: %s", err)
+		}
+		log.Debugf("projects: %v", projects)
+
+		fmt.Println()
+		selectedProject, err := goinp.SelectFromStrings("Select the iOS Project you usually build in Visual Studio / Xamarin Studio", projects)
+		if err != nil {
+			return printXamarinScanFinishedWithError("Failed to select Project: %s", err)
+		}
+		log.Debugf("selected project: %v", selectedProject)
+		projectToUse = selectedProject
+	}
+	xamarinCmd.Project = projectToUse
+
+	fmt.Println()
+	fmt.Println()
+	log.Println("🔦  Running a Release build, to get all the required code signing settings...")
+	codeSigningSettings, buildOutput, err := xamarinCmd.ScanCodeSigningSettings()
+	// save the build output into a debug log file
+	buildOutputFilePath := filepath.Join(absExportOutputDirPath, "xamarin-build-output.log")
+	{
+		log.Infof("  💡  "+colorstring.Yellow("Saving build output into file")+": %s", buildOutputFilePath)
+		if logWriteErr := fileutil.WriteStringToFile(buildOutputFilePath, buildOutput); logWriteErr != nil {
+			log.Errorf("Failed to save build output into file (%s), error: %s", buildOutputFilePath, logWriteErr)
+		} else if err != nil {
+			log.Infoln(colorstring.Yellow("Please check the logfile (" + buildOutputFilePath + ") to see what caused the error"))
+			log.Infoln(colorstring.Red("and make sure that you can build this Project from Visual Studio / Xamarin Studio!"))
+		}
+	}
+	if err != nil {
+		return printXamarinScanFinishedWithError("Failed to detect code signing settings: %s", err)
+	}
+	log.Debugf("codeSigningSettings: %#v", codeSigningSettings)
+
+	runInfo := scanRunInfo{
+		ProjectPath:   xamarinCmd.SolutionFilePath,
+		Scheme:        xamarinCmd.Project,
+		Configuration: paramXamarinConfiguration,
+		BuildLogPath:  buildOutputFilePath,
+	}
+	return exportCodeSigningFiles("Xamarin", absExportOutputDirPath, codeSigningSettings, runInfo)
+}
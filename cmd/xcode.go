@@ -2,9 +2,11 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 
 	log "github.com/Sirupsen/logrus"
 
@@ -31,8 +33,22 @@ var xcodeCmd = &cobra.Command{
 var (
 	paramXcodeProjectFilePath = ""
 	paramXcodeScheme          = ""
+	paramXcodeAllProfiles     = false
+	paramXcodeCI              = false
+	paramXcodeExportOutputDir = ""
+	paramXcodeP12Password     = ""
+	paramXcodeConfiguration   = ""
+	paramXcodeUpload          = false
+	paramXcodeAppSlug         = ""
+	paramXcodeDryRun          = false
+	paramXcodeExportMethod    = ""
+	paramXcodeTeamID          = ""
+	paramXcodeDestination     = ""
+	paramXcodeXcargs          = ""
 )
 
+var validXcodeExportMethods = []string{"development", "ad-hoc", "app-store", "enterprise"}
+
 func init() {
 	scanCmd.AddCommand(xcodeCmd)
 
@@ -42,6 +58,42 @@ func init() {
 	xcodeCmd.Flags().StringVar(&paramXcodeScheme,
 		"scheme", "",
 		"Xcode Scheme")
+	xcodeCmd.Flags().BoolVar(&paramXcodeAllProfiles,
+		"all-profiles", false,
+		"Export every matching Provisioning Profile, including expired and superseded ones (by default only the newest, unexpired profile per App ID / Distribution type / Team is exported)")
+	xcodeCmd.Flags().BoolVar(&paramXcodeCI,
+		"ci", false,
+		"Run non-interactively: disable every prompt and require --file, --scheme, --export-output-dir and --p12-password to be set. Auto-enabled when BITRISE_CI or CI is \"true\"")
+	xcodeCmd.Flags().StringVar(&paramXcodeExportOutputDir,
+		"export-output-dir", "",
+		"Directory to export the code signing files into (required in --ci mode)")
+	xcodeCmd.Flags().StringVar(&paramXcodeP12Password,
+		"p12-password", "",
+		"Password to use when exporting .p12 files (required in --ci mode, can also be set via CODESIGNDOC_P12_PASSWORD)")
+	xcodeCmd.Flags().StringVar(&paramXcodeConfiguration,
+		"configuration", "",
+		"Xcode build Configuration to Archive with (e.g. Release); defaults to the Scheme's own Archive configuration")
+	xcodeCmd.Flags().BoolVar(&paramXcodeUpload,
+		"upload", false,
+		"Upload the exported Certificates and Provisioning Profiles to a Bitrise app once the scan finishes")
+	xcodeCmd.Flags().StringVar(&paramXcodeAppSlug,
+		"app-slug", "",
+		"Bitrise App slug to upload to when --upload is set")
+	xcodeCmd.Flags().BoolVar(&paramXcodeDryRun,
+		"dry-run", false,
+		"With --upload: print what would be uploaded, without calling the Bitrise API")
+	xcodeCmd.Flags().StringVar(&paramXcodeExportMethod,
+		"export-method", "",
+		"Also run xcodebuild -exportArchive for this distribution method ("+strings.Join(validXcodeExportMethods, ", ")+"), to scan the Provisioning Profiles Xcode picks for it")
+	xcodeCmd.Flags().StringVar(&paramXcodeTeamID,
+		"team-id", "",
+		"Development Team ID to Archive (and Export) with, overriding the project's own setting")
+	xcodeCmd.Flags().StringVar(&paramXcodeDestination,
+		"destination", "",
+		"xcodebuild -destination value to Archive with (defaults to \"generic/platform=iOS\")")
+	xcodeCmd.Flags().StringVar(&paramXcodeXcargs,
+		"xcargs", "",
+		"Additional xcodebuild arguments to pass to the archive step, as a space separated \"KEY=VALUE ...\" list")
 }
 
 func printXcodeScanFinishedWithError(format string, args ...interface{}) error {
@@ -49,7 +101,29 @@ func printXcodeScanFinishedWithError(format string, args ...interface{}) error {
 }
 
 func scanXcodeProject(cmd *cobra.Command, args []string) error {
-	absExportOutputDirPath, err := initExportOutputDir()
+	ciMode := paramXcodeCI || isCIEnvironmentEnabled()
+
+	p12Password := paramXcodeP12Password
+	if p12Password == "" {
+		p12Password = os.Getenv("CODESIGNDOC_P12_PASSWORD")
+	}
+
+	if ciMode {
+		if err := requireCIFlag(paramXcodeProjectFilePath, "--file", "path to the Xcode Project/Workspace"); err != nil {
+			return printXcodeScanFinishedWithError("%s", err)
+		}
+		if err := requireCIFlag(paramXcodeScheme, "--scheme", "the Xcode Scheme to Archive"); err != nil {
+			return printXcodeScanFinishedWithError("%s", err)
+		}
+		if err := requireCIFlag(paramXcodeExportOutputDir, "--export-output-dir", "directory to export the code signing files into"); err != nil {
+			return printXcodeScanFinishedWithError("%s", err)
+		}
+		if err := requireCIFlag(p12Password, "--p12-password", "password to use when exporting .p12 files, or set CODESIGNDOC_P12_PASSWORD"); err != nil {
+			return printXcodeScanFinishedWithError("%s", err)
+		}
+	}
+
+	absExportOutputDirPath, err := resolveExportOutputDir(paramXcodeExportOutputDir)
 	if err != nil {
 		return printXcodeScanFinishedWithError("Failed to prepare Export directory: %s", err)
 	}
@@ -69,8 +143,24 @@ func scanXcodeProject(cmd *cobra.Command, args []string) error {
 		projectPath = projpth
 	}
 	log.Debugf("projectPath: %s", projectPath)
+	if paramXcodeExportMethod != "" && !isValidXcodeExportMethod(paramXcodeExportMethod) {
+		return printXcodeScanFinishedWithError("Invalid --export-method %q, must be one of: %s",
+			paramXcodeExportMethod, strings.Join(validXcodeExportMethods, ", "))
+	}
+
+	xcargs, err := splitXcargs(paramXcodeXcargs)
+	if err != nil {
+		return printXcodeScanFinishedWithError("Failed to parse --xcargs: %s", err)
+	}
+
 	xcodeCmd := xcode.CommandModel{
 		ProjectFilePath: projectPath,
+		Configuration:   paramXcodeConfiguration,
+		ExportMethod:    paramXcodeExportMethod,
+		TeamID:          paramXcodeTeamID,
+		Destination:     paramXcodeDestination,
+		XcodeBuildArgs:  xcargs,
+		P12Password:     p12Password,
 	}
 
 	schemeToUse := paramXcodeScheme
@@ -114,15 +204,48 @@ func scanXcodeProject(cmd *cobra.Command, args []string) error {
 		}
 	}
 	if err != nil {
+		if distLogsPath, bundleErr := bundleIDEDistributionLogs(xcodebuildOutput, absExportOutputDirPath); bundleErr != nil {
+			log.Warnf("Failed to bundle IDEDistribution logs: %s", bundleErr)
+		} else if distLogsPath != "" {
+			log.Infoln(colorstring.Yellow("Found IDEDistribution logs, copied to: " + distLogsPath))
+			return printXcodeScanFinishedWithError("Failed to detect code signing settings: %s\nCheck the IDEDistribution logs for the real error: %s", err, distLogsPath)
+		}
 		return printXcodeScanFinishedWithError("Failed to detect code signing settings: %s", err)
 	}
 	log.Debugf("codeSigningSettings: %#v", codeSigningSettings)
 
-	return exportCodeSigningFiles("Xcode", absExportOutputDirPath, codeSigningSettings)
+	runInfo := scanRunInfo{
+		ProjectPath:   xcodeCmd.ProjectFilePath,
+		Scheme:        xcodeCmd.Scheme,
+		Configuration: paramXcodeConfiguration,
+		BuildLogPath:  xcodebuildOutputFilePath,
+	}
+	if err := exportCodeSigningFiles("Xcode", absExportOutputDirPath, codeSigningSettings, runInfo); err != nil {
+		return err
+	}
+
+	if paramXcodeUpload {
+		fmt.Println()
+		log.Println("🔦  Uploading the exported files to Bitrise...")
+		if err := performUpload(absExportOutputDirPath, paramXcodeAppSlug, paramXcodeDryRun); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
+// exportProvisioningProfiles copies the subset of provProfileFileInfos that
+// selectProvisioningProfilesToExport selects into exportTargetDirPath, and
+// returns that subset so callers can describe what actually landed on disk
+// (e.g. in the export manifest) instead of the unfiltered input.
 func exportProvisioningProfiles(provProfileFileInfos []provprofile.ProvisioningProfileFileInfoModel,
-	exportTargetDirPath string) error {
+	exportTargetDirPath string) ([]provprofile.ProvisioningProfileFileInfoModel, error) {
+
+	provProfileFileInfos, err := selectProvisioningProfilesToExport(provProfileFileInfos, paramXcodeAllProfiles)
+	if err != nil {
+		return nil, err
+	}
 
 	for _, aProvProfileFileInfo := range provProfileFileInfos {
 		log.Infoln("   " + colorstring.Green("Exporting Provisioning Profile:") + " " + aProvProfileFileInfo.ProvisioningProfileInfo.Name)
@@ -130,11 +253,11 @@ func exportProvisioningProfiles(provProfileFileInfos []provprofile.ProvisioningP
 		exportFileName := provProfileExportFileName(aProvProfileFileInfo)
 		exportPth := filepath.Join(exportTargetDirPath, exportFileName)
 		if err := cmdex.RunCommand("cp", aProvProfileFileInfo.Path, exportPth); err != nil {
-			return fmt.Errorf("Failed to copy Provisioning Profile (from: %s) (to: %s), error: %s",
+			return nil, fmt.Errorf("Failed to copy Provisioning Profile (from: %s) (to: %s), error: %s",
 				aProvProfileFileInfo.Path, exportPth, err)
 		}
 	}
-	return nil
+	return provProfileFileInfos, nil
 }
 
 func provProfileExportFileName(provProfileFileInfo provprofile.ProvisioningProfileFileInfoModel) string {
@@ -151,3 +274,134 @@ func provProfileExportFileName(provProfileFileInfo provprofile.ProvisioningProfi
 
 	return provProfileFileInfo.ProvisioningProfileInfo.UUID + "." + safeTitle + extension
 }
+
+// provProfileGroupKey identifies the "slot" a Provisioning Profile fills:
+// profiles sharing a key are interchangeable, so only the newest unexpired
+// one among them needs to be exported.
+type provProfileGroupKey struct {
+	bundleID         string
+	distributionType string
+	teamID           string
+}
+
+func provProfileGroupKeyFor(info provprofile.ProvisioningProfileInfoModel) provProfileGroupKey {
+	return provProfileGroupKey{
+		bundleID:         info.BundleIdentifier(),
+		distributionType: info.DistributionType(),
+		teamID:           info.TeamID,
+	}
+}
+
+// selectProvisioningProfilesToExport drops expired profiles and, unless
+// includeAll is set, keeps only the newest (by CreationDate) profile per
+// App ID + Distribution type + Team. It fails with a clear error if a group
+// that had at least one profile ends up with none after expiry filtering.
+func selectProvisioningProfilesToExport(provProfileFileInfos []provprofile.ProvisioningProfileFileInfoModel,
+	includeAll bool) ([]provprofile.ProvisioningProfileFileInfoModel, error) {
+
+	if includeAll {
+		return provProfileFileInfos, nil
+	}
+
+	now := time.Now()
+	hasUnexpired := map[provProfileGroupKey]bool{}
+
+	unexpired := make([]provprofile.ProvisioningProfileFileInfoModel, 0, len(provProfileFileInfos))
+	for _, info := range provProfileFileInfos {
+		key := provProfileGroupKeyFor(info.ProvisioningProfileInfo)
+		if info.ProvisioningProfileInfo.IsExpired(now) {
+			log.Warn(colorstring.Yellow(fmt.Sprintf("  Skipping Provisioning Profile %s (%s): expired at %s",
+				info.ProvisioningProfileInfo.Name, info.ProvisioningProfileInfo.UUID,
+				info.ProvisioningProfileInfo.ExpirationDate.Format(time.RFC1123))))
+			continue
+		}
+		hasUnexpired[key] = true
+		unexpired = append(unexpired, info)
+	}
+
+	for _, info := range provProfileFileInfos {
+		key := provProfileGroupKeyFor(info.ProvisioningProfileInfo)
+		if !hasUnexpired[key] {
+			return nil, fmt.Errorf("no unexpired Provisioning Profile found for bundle id %q (distribution type: %s, team: %s) - the Archive can't be signed without one",
+				key.bundleID, key.distributionType, key.teamID)
+		}
+	}
+
+	latestByKey := map[provProfileGroupKey]provprofile.ProvisioningProfileFileInfoModel{}
+	for _, info := range unexpired {
+		key := provProfileGroupKeyFor(info.ProvisioningProfileInfo)
+		current, alreadySeen := latestByKey[key]
+		if !alreadySeen {
+			latestByKey[key] = info
+			continue
+		}
+
+		newer, older := current, info
+		if info.ProvisioningProfileInfo.CreationDate.After(current.ProvisioningProfileInfo.CreationDate) {
+			newer, older = info, current
+		}
+		log.Warn(colorstring.Yellow(fmt.Sprintf("  Skipping Provisioning Profile %s (%s): superseded by newer profile %s",
+			older.ProvisioningProfileInfo.Name, older.ProvisioningProfileInfo.UUID, newer.ProvisioningProfileInfo.UUID)))
+		latestByKey[key] = newer
+	}
+
+	selected := make([]provprofile.ProvisioningProfileFileInfoModel, 0, len(latestByKey))
+	for _, info := range latestByKey {
+		selected = append(selected, info)
+	}
+	return selected, nil
+}
+
+// ideDistributionLogsPathPattern matches the line xcodebuild prints when it
+// creates the .xcdistributionlogs bundle for a failed Archive/Export, e.g.:
+//   IDEDistribution: -[IDEDistributionLogging _createLoggingBundleAtPath:]: Created bundle at path '/private/var/.../whatever.xcdistributionlogs'
+var ideDistributionLogsPathPattern = regexp.MustCompile(`IDEDistribution: .*_createLoggingBundleAtPath:.*: Created bundle at path '([^']+)'`)
+
+// bundleIDEDistributionLogs scans xcodebuildOutput for the .xcdistributionlogs
+// bundle path xcodebuild logs on failure, and copies it next to
+// xcodebuild-output.log in absExportOutputDirPath so it survives after the
+// temp dir xcodebuild created it in gets cleaned up. Returns "" if no such
+// bundle was mentioned in the output.
+func bundleIDEDistributionLogs(xcodebuildOutput, absExportOutputDirPath string) (string, error) {
+	match := ideDistributionLogsPathPattern.FindStringSubmatch(xcodebuildOutput)
+	if match == nil {
+		return "", nil
+	}
+	sourceLogsPath := match[1]
+
+	if _, err := os.Stat(sourceLogsPath); err != nil {
+		return "", fmt.Errorf("IDEDistribution logs bundle not found at %s: %s", sourceLogsPath, err)
+	}
+
+	targetLogsPath := filepath.Join(absExportOutputDirPath, filepath.Base(sourceLogsPath))
+	if err := cmdex.RunCommand("cp", "-R", sourceLogsPath, targetLogsPath); err != nil {
+		return "", fmt.Errorf("failed to copy IDEDistribution logs (from: %s) (to: %s): %s", sourceLogsPath, targetLogsPath, err)
+	}
+	return targetLogsPath, nil
+}
+
+func isValidXcodeExportMethod(exportMethod string) bool {
+	for _, valid := range validXcodeExportMethods {
+		if exportMethod == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// splitXcargs parses a space separated "KEY=VALUE ..." string, as accepted
+// by the --xcargs flag, into individual xcodebuild arguments.
+func splitXcargs(xcargs string) ([]string, error) {
+	xcargs = strings.TrimSpace(xcargs)
+	if xcargs == "" {
+		return nil, nil
+	}
+
+	args := strings.Fields(xcargs)
+	for _, arg := range args {
+		if !strings.Contains(arg, "=") {
+			return nil, fmt.Errorf("invalid xcarg %q, expected the form KEY=VALUE", arg)
+		}
+	}
+	return args, nil
+}
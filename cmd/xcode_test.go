@@ -0,0 +1,211 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bitrise-tools/codesigndoc/provprofile"
+)
+
+func mockProvProfile(uuid, bundleID, teamID string, provisionsAllDevices bool, creationDate, expirationDate time.Time) provprofile.ProvisioningProfileFileInfoModel {
+	return provprofile.ProvisioningProfileFileInfoModel{
+		Path: uuid + ".mobileprovision",
+		ProvisioningProfileInfo: provprofile.ProvisioningProfileInfoModel{
+			UUID:   uuid,
+			Name:   "Profile " + uuid,
+			TeamID: teamID,
+			Entitlements: map[string]interface{}{
+				"application-identifier": teamID + "." + bundleID,
+			},
+			ProvisionsAllDevices: provisionsAllDevices,
+			CreationDate:         creationDate,
+			ExpirationDate:       expirationDate,
+		},
+	}
+}
+
+func TestProvProfileGroupKeyFor(t *testing.T) {
+	now := time.Now()
+	profile := mockProvProfile("uuid-1", "com.example.app", "TEAM1", true, now, now.Add(time.Hour))
+
+	got := provProfileGroupKeyFor(profile.ProvisioningProfileInfo)
+	want := provProfileGroupKey{
+		bundleID:         "com.example.app",
+		distributionType: "enterprise",
+		teamID:           "TEAM1",
+	}
+	if got != want {
+		t.Errorf("provProfileGroupKeyFor() = %+v, want %+v", got, want)
+	}
+}
+
+func TestSelectProvisioningProfilesToExport_IncludeAll(t *testing.T) {
+	now := time.Now()
+	profiles := []provprofile.ProvisioningProfileFileInfoModel{
+		mockProvProfile("uuid-1", "com.example.app", "TEAM1", true, now, now.Add(-time.Hour)),
+	}
+
+	selected, err := selectProvisioningProfilesToExport(profiles, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(selected) != 1 {
+		t.Fatalf("expected includeAll to keep every profile (even expired ones), got %d", len(selected))
+	}
+}
+
+func TestSelectProvisioningProfilesToExport_TieBreakByCreationDate(t *testing.T) {
+	now := time.Now()
+	older := mockProvProfile("uuid-older", "com.example.app", "TEAM1", true, now.Add(-48*time.Hour), now.Add(time.Hour))
+	newer := mockProvProfile("uuid-newer", "com.example.app", "TEAM1", true, now.Add(-time.Hour), now.Add(time.Hour))
+
+	selected, err := selectProvisioningProfilesToExport([]provprofile.ProvisioningProfileFileInfoModel{older, newer}, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(selected) != 1 {
+		t.Fatalf("expected exactly one profile to survive the group, got %d", len(selected))
+	}
+	if selected[0].ProvisioningProfileInfo.UUID != "uuid-newer" {
+		t.Errorf("selected %s, want the newer profile (uuid-newer) to win the tie-break", selected[0].ProvisioningProfileInfo.UUID)
+	}
+}
+
+func TestSelectProvisioningProfilesToExport_AllExpiredInGroupErrors(t *testing.T) {
+	now := time.Now()
+	expired := mockProvProfile("uuid-1", "com.example.app", "TEAM1", true, now.Add(-48*time.Hour), now.Add(-time.Hour))
+
+	_, err := selectProvisioningProfilesToExport([]provprofile.ProvisioningProfileFileInfoModel{expired}, false)
+	if err == nil {
+		t.Fatal("expected an error when a group has at least one profile but all are expired, got nil")
+	}
+}
+
+func TestSelectProvisioningProfilesToExport_DropsExpiredWhenUnexpiredSiblingExists(t *testing.T) {
+	now := time.Now()
+	expired := mockProvProfile("uuid-expired", "com.example.app", "TEAM1", true, now.Add(-48*time.Hour), now.Add(-time.Hour))
+	unexpired := mockProvProfile("uuid-live", "com.example.app", "TEAM1", true, now.Add(-time.Hour), now.Add(time.Hour))
+
+	selected, err := selectProvisioningProfilesToExport([]provprofile.ProvisioningProfileFileInfoModel{expired, unexpired}, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(selected) != 1 || selected[0].ProvisioningProfileInfo.UUID != "uuid-live" {
+		t.Errorf("selected = %+v, want only uuid-live", selected)
+	}
+}
+
+func TestIsValidXcodeExportMethod(t *testing.T) {
+	for _, valid := range validXcodeExportMethods {
+		if !isValidXcodeExportMethod(valid) {
+			t.Errorf("isValidXcodeExportMethod(%q) = false, want true", valid)
+		}
+	}
+	if isValidXcodeExportMethod("not-a-real-method") {
+		t.Error("isValidXcodeExportMethod(\"not-a-real-method\") = true, want false")
+	}
+}
+
+func TestSplitXcargs(t *testing.T) {
+	tests := []struct {
+		name    string
+		xcargs  string
+		want    []string
+		wantErr bool
+	}{
+		{name: "empty", xcargs: "", want: nil},
+		{name: "single arg", xcargs: "DEVELOPMENT_TEAM=ABCDE12345", want: []string{"DEVELOPMENT_TEAM=ABCDE12345"}},
+		{name: "multiple args", xcargs: "ONE=1 TWO=2", want: []string{"ONE=1", "TWO=2"}},
+		{name: "missing =", xcargs: "NOTANARG", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := splitXcargs(test.xcargs)
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("expected an error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if len(got) != len(test.want) {
+				t.Fatalf("splitXcargs() = %v, want %v", got, test.want)
+			}
+			for i := range got {
+				if got[i] != test.want[i] {
+					t.Errorf("splitXcargs()[%d] = %q, want %q", i, got[i], test.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSelectProvisioningProfilesToExport_DifferentGroupsBothKept(t *testing.T) {
+	now := time.Now()
+	appA := mockProvProfile("uuid-a", "com.example.app-a", "TEAM1", true, now, now.Add(time.Hour))
+	appB := mockProvProfile("uuid-b", "com.example.app-b", "TEAM1", true, now, now.Add(time.Hour))
+
+	selected, err := selectProvisioningProfilesToExport([]provprofile.ProvisioningProfileFileInfoModel{appA, appB}, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(selected) != 2 {
+		t.Errorf("expected both distinct App ID groups to be kept, got %d", len(selected))
+	}
+}
+
+func TestBundleIDEDistributionLogs(t *testing.T) {
+	sourceDir, err := ioutil.TempDir("", "codesigndoc-distlogs-source")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(sourceDir)
+	sourceLogsPath := filepath.Join(sourceDir, "whatever.xcdistributionlogs")
+	if err := os.Mkdir(sourceLogsPath, 0755); err != nil {
+		t.Fatalf("failed to create fixture logs bundle: %s", err)
+	}
+
+	exportDir, err := ioutil.TempDir("", "codesigndoc-distlogs-export")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(exportDir)
+
+	xcodebuildOutput := "some preceding output\n" +
+		"IDEDistribution: -[IDEDistributionLogging _createLoggingBundleAtPath:]: Created bundle at path '" + sourceLogsPath + "'\n" +
+		"some trailing output"
+
+	gotPath, err := bundleIDEDistributionLogs(xcodebuildOutput, exportDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	wantPath := filepath.Join(exportDir, "whatever.xcdistributionlogs")
+	if gotPath != wantPath {
+		t.Errorf("bundleIDEDistributionLogs() = %q, want %q", gotPath, wantPath)
+	}
+	if _, err := os.Stat(wantPath); err != nil {
+		t.Errorf("expected the logs bundle to be copied to %q: %s", wantPath, err)
+	}
+}
+
+func TestBundleIDEDistributionLogs_NoMatch(t *testing.T) {
+	exportDir, err := ioutil.TempDir("", "codesigndoc-distlogs-export")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(exportDir)
+
+	gotPath, err := bundleIDEDistributionLogs("no distribution logs mentioned here", exportDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if gotPath != "" {
+		t.Errorf("bundleIDEDistributionLogs() = %q, want empty when xcodebuild output mentions no logs bundle", gotPath)
+	}
+}
@@ -0,0 +1,15 @@
+// Package models holds data types shared between the Xcode and Xamarin
+// scanners and the cmd package that exports their results.
+package models
+
+import (
+	"github.com/bitrise-tools/codesigndoc/certificateutil"
+	"github.com/bitrise-tools/codesigndoc/provprofile"
+)
+
+// CodeSigningSettings is the set of code signing assets a project scan
+// (Xcode Archive or Xamarin build) determined are required to sign the app.
+type CodeSigningSettings struct {
+	Certificates         []certificateutil.CertificateInfoModel
+	ProvisioningProfiles []provprofile.ProvisioningProfileFileInfoModel
+}
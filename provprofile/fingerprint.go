@@ -0,0 +1,25 @@
+package provprofile
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+func sha1Sum(data []byte) []byte {
+	sum := sha1.Sum(data)
+	return sum[:]
+}
+
+func sha256Sum(data []byte) []byte {
+	sum := sha256.Sum256(data)
+	return sum[:]
+}
+
+// fingerprintHex returns the upper-case, colon-free hex digest of data using
+// the given hash function - matching the format `security cms -D` prints for
+// certificate fingerprints.
+func fingerprintHex(data []byte, hashFn func([]byte) []byte) string {
+	return strings.ToUpper(hex.EncodeToString(hashFn(data)))
+}
@@ -0,0 +1,188 @@
+// Package provprofile implements reading and parsing of Apple code signing
+// Provisioning Profiles (.mobileprovision / .provisionprofile files).
+package provprofile
+
+import (
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/fullsailor/pkcs7"
+	"howett.net/plist"
+)
+
+// DeveloperCertificateModel describes a single code signing certificate
+// embedded in a Provisioning Profile's DeveloperCertificates array.
+type DeveloperCertificateModel struct {
+	CommonName        string
+	TeamID            string
+	SHA1Fingerprint   string
+	SHA256Fingerprint string
+	NotBefore         time.Time
+	NotAfter          time.Time
+}
+
+// ProvisioningProfileInfoModel is the structured representation of the
+// property list embedded in a Provisioning Profile's PKCS7 signed data.
+type ProvisioningProfileInfoModel struct {
+	UUID                  string
+	Name                  string
+	TeamID                string
+	TeamName              string
+	AppIDName             string
+	Entitlements          map[string]interface{}
+	ProvisionedDevices    []string
+	DeveloperCertificates []DeveloperCertificateModel
+	CreationDate          time.Time
+	ExpirationDate        time.Time
+	ProvisionsAllDevices  bool
+	Platform              []string
+}
+
+// ProvisioningProfileFileInfoModel pairs the path of a Provisioning Profile
+// file on disk with its parsed content.
+type ProvisioningProfileFileInfoModel struct {
+	Path                    string
+	ProvisioningProfileInfo ProvisioningProfileInfoModel
+}
+
+// rawProvisioningProfilePlist mirrors the property list keys Apple embeds in
+// a Provisioning Profile, as found inside the PKCS7 eContent.
+type rawProvisioningProfilePlist struct {
+	UUID                  string                 `plist:"UUID"`
+	Name                  string                 `plist:"Name"`
+	TeamIdentifier        []string               `plist:"TeamIdentifier"`
+	TeamName              string                 `plist:"TeamName"`
+	AppIDName             string                 `plist:"AppIDName"`
+	Entitlements          map[string]interface{} `plist:"Entitlements"`
+	ProvisionedDevices    []string               `plist:"ProvisionedDevices"`
+	ProvisionsAllDevices  bool                   `plist:"ProvisionsAllDevices"`
+	Platform              []string               `plist:"Platform"`
+	CreationDate          time.Time              `plist:"CreationDate"`
+	ExpirationDate        time.Time              `plist:"ExpirationDate"`
+	DeveloperCertificates [][]byte               `plist:"DeveloperCertificates"`
+}
+
+// NewProvisioningProfileFileInfoModelFromFile reads a .mobileprovision /
+// .provisionprofile file at path, unwraps its PKCS7 SignedData envelope and
+// parses the embedded XML property list into a ProvisioningProfileInfoModel.
+//
+// No signature verification is performed - codesigndoc only needs the
+// content, the trust decision is Xcode's / the App Store's to make.
+func NewProvisioningProfileFileInfoModelFromFile(path string) (ProvisioningProfileFileInfoModel, error) {
+	info, err := ParseProvisioningProfileFile(path)
+	if err != nil {
+		return ProvisioningProfileFileInfoModel{}, err
+	}
+	return ProvisioningProfileFileInfoModel{
+		Path:                    path,
+		ProvisioningProfileInfo: info,
+	}, nil
+}
+
+// ParseProvisioningProfileFile reads a Provisioning Profile file from path
+// and returns its parsed metadata.
+func ParseProvisioningProfileFile(path string) (ProvisioningProfileInfoModel, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return ProvisioningProfileInfoModel{}, fmt.Errorf("failed to read Provisioning Profile (%s): %s", path, err)
+	}
+	return ParseProvisioningProfileContent(content)
+}
+
+// ParseProvisioningProfileContent unwraps the CMS/PKCS7 SignedData envelope
+// of a Provisioning Profile's raw content and decodes the embedded plist.
+func ParseProvisioningProfileContent(content []byte) (ProvisioningProfileInfoModel, error) {
+	p7, err := pkcs7.Parse(content)
+	if err != nil {
+		return ProvisioningProfileInfoModel{}, fmt.Errorf("failed to parse PKCS7 SignedData envelope: %s", err)
+	}
+
+	var raw rawProvisioningProfilePlist
+	if _, err := plist.Unmarshal(p7.Content, &raw); err != nil {
+		return ProvisioningProfileInfoModel{}, fmt.Errorf("failed to decode embedded Provisioning Profile plist: %s", err)
+	}
+
+	certs := make([]DeveloperCertificateModel, 0, len(raw.DeveloperCertificates))
+	for _, certDER := range raw.DeveloperCertificates {
+		cert, err := x509.ParseCertificate(certDER)
+		if err != nil {
+			return ProvisioningProfileInfoModel{}, fmt.Errorf("failed to parse embedded Developer Certificate: %s", err)
+		}
+		certs = append(certs, developerCertificateModelFromX509(cert))
+	}
+
+	teamID := ""
+	if len(raw.TeamIdentifier) > 0 {
+		teamID = raw.TeamIdentifier[0]
+	}
+
+	return ProvisioningProfileInfoModel{
+		UUID:                  raw.UUID,
+		Name:                  raw.Name,
+		TeamID:                teamID,
+		TeamName:              raw.TeamName,
+		AppIDName:             raw.AppIDName,
+		Entitlements:          raw.Entitlements,
+		ProvisionedDevices:    raw.ProvisionedDevices,
+		DeveloperCertificates: certs,
+		CreationDate:          raw.CreationDate,
+		ExpirationDate:        raw.ExpirationDate,
+		ProvisionsAllDevices:  raw.ProvisionsAllDevices,
+		Platform:              raw.Platform,
+	}, nil
+}
+
+func developerCertificateModelFromX509(cert *x509.Certificate) DeveloperCertificateModel {
+	return DeveloperCertificateModel{
+		CommonName:        cert.Subject.CommonName,
+		TeamID:            organizationalUnit(cert),
+		SHA1Fingerprint:   fingerprintHex(cert.Raw, sha1Sum),
+		SHA256Fingerprint: fingerprintHex(cert.Raw, sha256Sum),
+		NotBefore:         cert.NotBefore,
+		NotAfter:          cert.NotAfter,
+	}
+}
+
+func organizationalUnit(cert *x509.Certificate) string {
+	if len(cert.Subject.OrganizationalUnit) == 0 {
+		return ""
+	}
+	return cert.Subject.OrganizationalUnit[0]
+}
+
+// BundleIdentifier returns the App ID the Provisioning Profile was issued
+// for, derived from its `application-identifier` entitlement (which is
+// `<TeamID>.<bundle id>`).
+func (info ProvisioningProfileInfoModel) BundleIdentifier() string {
+	appID, ok := info.Entitlements["application-identifier"].(string)
+	if !ok {
+		return ""
+	}
+	return strings.TrimPrefix(appID, info.TeamID+".")
+}
+
+// DistributionType infers the Provisioning Profile's distribution channel
+// (development, ad-hoc, enterprise or app-store) from its device list and
+// entitlements, the same way Xcode itself categorizes profiles.
+func (info ProvisioningProfileInfoModel) DistributionType() string {
+	getTaskAllow, _ := info.Entitlements["get-task-allow"].(bool)
+	switch {
+	case len(info.ProvisionedDevices) > 0 && getTaskAllow:
+		return "development"
+	case len(info.ProvisionedDevices) > 0:
+		return "ad-hoc"
+	case info.ProvisionsAllDevices:
+		return "enterprise"
+	default:
+		return "app-store"
+	}
+}
+
+// IsExpired reports whether the Provisioning Profile's ExpirationDate is in
+// the past, relative to now.
+func (info ProvisioningProfileInfoModel) IsExpired(now time.Time) bool {
+	return now.After(info.ExpirationDate)
+}
@@ -0,0 +1,127 @@
+package provprofile
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func TestParseProvisioningProfileContent(t *testing.T) {
+	content, err := ioutil.ReadFile("testdata/test.mobileprovision")
+	if err != nil {
+		t.Fatalf("failed to read testdata: %s", err)
+	}
+
+	info, err := ParseProvisioningProfileContent(content)
+	if err != nil {
+		t.Fatalf("ParseProvisioningProfileContent() error = %s", err)
+	}
+
+	if want := "12345678-1234-1234-1234-123456789abc"; info.UUID != want {
+		t.Errorf("UUID = %q, want %q", info.UUID, want)
+	}
+	if want := "Test Wildcard Profile"; info.Name != want {
+		t.Errorf("Name = %q, want %q", info.Name, want)
+	}
+	if want := "ABCDE12345"; info.TeamID != want {
+		t.Errorf("TeamID = %q, want %q", info.TeamID, want)
+	}
+	if want := "Test Team"; info.TeamName != want {
+		t.Errorf("TeamName = %q, want %q", info.TeamName, want)
+	}
+	if want := []string{"abcdef0123456789abcdef0123456789abcdef01"}; len(info.ProvisionedDevices) != len(want) || info.ProvisionedDevices[0] != want[0] {
+		t.Errorf("ProvisionedDevices = %v, want %v", info.ProvisionedDevices, want)
+	}
+	if info.ProvisionsAllDevices {
+		t.Errorf("ProvisionsAllDevices = true, want false")
+	}
+	if want := "ABCDE12345.com.example.testapp"; info.Entitlements["application-identifier"] != want {
+		t.Errorf("Entitlements[application-identifier] = %v, want %q", info.Entitlements["application-identifier"], want)
+	}
+}
+
+func TestParseProvisioningProfileContent_InvalidContent(t *testing.T) {
+	if _, err := ParseProvisioningProfileContent([]byte("not a PKCS7 envelope")); err == nil {
+		t.Error("expected an error for non-PKCS7 content, got nil")
+	}
+}
+
+func TestBundleIdentifier(t *testing.T) {
+	info := ProvisioningProfileInfoModel{
+		TeamID: "ABCDE12345",
+		Entitlements: map[string]interface{}{
+			"application-identifier": "ABCDE12345.com.example.testapp",
+		},
+	}
+
+	if want := "com.example.testapp"; info.BundleIdentifier() != want {
+		t.Errorf("BundleIdentifier() = %q, want %q", info.BundleIdentifier(), want)
+	}
+}
+
+func TestBundleIdentifier_MissingEntitlement(t *testing.T) {
+	info := ProvisioningProfileInfoModel{TeamID: "ABCDE12345"}
+
+	if want := ""; info.BundleIdentifier() != want {
+		t.Errorf("BundleIdentifier() = %q, want %q", info.BundleIdentifier(), want)
+	}
+}
+
+func TestDistributionType(t *testing.T) {
+	tests := []struct {
+		name string
+		info ProvisioningProfileInfoModel
+		want string
+	}{
+		{
+			name: "development: has devices, get-task-allow",
+			info: ProvisioningProfileInfoModel{
+				ProvisionedDevices: []string{"device1"},
+				Entitlements:       map[string]interface{}{"get-task-allow": true},
+			},
+			want: "development",
+		},
+		{
+			name: "ad-hoc: has devices, no get-task-allow",
+			info: ProvisioningProfileInfoModel{
+				ProvisionedDevices: []string{"device1"},
+				Entitlements:       map[string]interface{}{"get-task-allow": false},
+			},
+			want: "ad-hoc",
+		},
+		{
+			name: "enterprise: no devices, provisions all",
+			info: ProvisioningProfileInfoModel{
+				ProvisionsAllDevices: true,
+			},
+			want: "enterprise",
+		},
+		{
+			name: "app-store: no devices, doesn't provision all",
+			info: ProvisioningProfileInfoModel{},
+			want: "app-store",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.info.DistributionType(); got != test.want {
+				t.Errorf("DistributionType() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestIsExpired(t *testing.T) {
+	now := time.Date(2026, 7, 25, 0, 0, 0, 0, time.UTC)
+
+	expired := ProvisioningProfileInfoModel{ExpirationDate: now.Add(-time.Hour)}
+	if !expired.IsExpired(now) {
+		t.Error("IsExpired() = false for a past ExpirationDate, want true")
+	}
+
+	notExpired := ProvisioningProfileInfoModel{ExpirationDate: now.Add(time.Hour)}
+	if notExpired.IsExpired(now) {
+		t.Error("IsExpired() = true for a future ExpirationDate, want false")
+	}
+}
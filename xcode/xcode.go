@@ -0,0 +1,357 @@
+// Package xcode scans an Xcode project/workspace by Archiving it and
+// inspecting the resulting .xcarchive for the code signing settings Xcode
+// picked to sign the build.
+package xcode
+
+import (
+	"archive/zip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/bitrise-io/go-utils/cmdex"
+	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/bitrise-tools/codesigndoc/certificateutil"
+	"github.com/bitrise-tools/codesigndoc/models"
+	"github.com/bitrise-tools/codesigndoc/provprofile"
+)
+
+// CommandModel describes the Xcode project/workspace to scan, and every
+// knob that influences how `xcodebuild archive` (and, optionally,
+// `xcodebuild -exportArchive`) is invoked.
+type CommandModel struct {
+	ProjectFilePath string
+	Scheme          string
+	Configuration   string
+
+	// ExportMethod, when set, additionally runs `xcodebuild -exportArchive`
+	// with a generated exportOptions.plist for this distribution channel,
+	// so the Provisioning Profiles Xcode actually picks for it are scanned
+	// too (Debug and Release signing commonly differ).
+	ExportMethod   string
+	TeamID         string
+	Destination    string
+	XcodeBuildArgs []string
+
+	// P12Password is used to export any Keychain identity required to sign
+	// with the Provisioning Profiles found, into a temporary .p12 file.
+	P12Password string
+}
+
+const defaultDestination = "generic/platform=iOS"
+
+// ScanSchemes lists the Schemes defined by the Project/Workspace.
+func (cmd CommandModel) ScanSchemes() ([]string, error) {
+	out, err := cmd.runXcodebuild("-list")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Schemes: %s: %s", err, out)
+	}
+	return parseSchemesFromXcodebuildList(out), nil
+}
+
+func parseSchemesFromXcodebuildList(xcodebuildListOutput string) []string {
+	schemes := []string{}
+	inSchemesSection := false
+	for _, line := range strings.Split(xcodebuildListOutput, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "Schemes:" {
+			inSchemesSection = true
+			continue
+		}
+		if inSchemesSection {
+			if trimmed == "" {
+				break
+			}
+			schemes = append(schemes, trimmed)
+		}
+	}
+	return schemes
+}
+
+// ScanCodeSigningSettings runs an Xcode Archive (and, if ExportMethod is
+// set, an Export) of cmd's Scheme, and returns the Certificates and
+// Provisioning Profiles required to reproduce it.
+func (cmd CommandModel) ScanCodeSigningSettings() (models.CodeSigningSettings, string, error) {
+	var combinedOutput string
+
+	archivePath, archiveOutput, err := cmd.archive()
+	combinedOutput += archiveOutput
+	if err != nil {
+		return models.CodeSigningSettings{}, combinedOutput, fmt.Errorf("xcodebuild archive failed: %s", err)
+	}
+
+	provProfiles, err := collectProvisioningProfilesFromAppDir(filepath.Join(archivePath, "Products", "Applications"))
+	if err != nil {
+		return models.CodeSigningSettings{}, combinedOutput, err
+	}
+
+	if cmd.ExportMethod != "" {
+		exportDirPath, exportOutput, err := cmd.exportArchive(archivePath)
+		combinedOutput += exportOutput
+		if err != nil {
+			return models.CodeSigningSettings{}, combinedOutput, fmt.Errorf("xcodebuild -exportArchive failed: %s", err)
+		}
+
+		exportedProfiles, err := collectProvisioningProfilesFromExportDir(exportDirPath)
+		if err != nil {
+			return models.CodeSigningSettings{}, combinedOutput, err
+		}
+		provProfiles = append(provProfiles, exportedProfiles...)
+	}
+
+	certs, err := discoverRequiredCertificates(provProfiles, cmd.P12Password)
+	if err != nil {
+		return models.CodeSigningSettings{}, combinedOutput, err
+	}
+
+	codeSigningSettings := models.CodeSigningSettings{
+		Certificates:         certs,
+		ProvisioningProfiles: provProfiles,
+	}
+	return codeSigningSettings, combinedOutput, nil
+}
+
+func (cmd CommandModel) archive() (string, string, error) {
+	archivePath, err := pathutil.NormalizedOSTempDirPath("xcodesigndoc-archive")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create temp dir for the Archive: %s", err)
+	}
+	archivePath = filepath.Join(archivePath, cmd.Scheme+".xcarchive")
+
+	args := cmd.archiveArgs(archivePath)
+	out, err := cmd.runXcodebuild(args...)
+	return archivePath, out, err
+}
+
+func (cmd CommandModel) archiveArgs(archivePath string) []string {
+	args := []string{"archive", "-scheme", cmd.Scheme, "-archivePath", archivePath}
+
+	if strings.HasSuffix(cmd.ProjectFilePath, ".xcworkspace") {
+		args = append([]string{"-workspace", cmd.ProjectFilePath}, args...)
+	} else {
+		args = append([]string{"-project", cmd.ProjectFilePath}, args...)
+	}
+
+	if cmd.Configuration != "" {
+		args = append(args, "-configuration", cmd.Configuration)
+	}
+
+	destination := cmd.Destination
+	if destination == "" {
+		destination = defaultDestination
+	}
+	args = append(args, "-destination", destination)
+
+	if cmd.TeamID != "" {
+		args = append(args, fmt.Sprintf("DEVELOPMENT_TEAM=%s", cmd.TeamID))
+	}
+
+	return append(args, cmd.XcodeBuildArgs...)
+}
+
+func (cmd CommandModel) exportArchive(archivePath string) (string, string, error) {
+	exportDirPath, err := pathutil.NormalizedOSTempDirPath("xcodesigndoc-export")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create temp dir for the Export: %s", err)
+	}
+
+	exportOptionsPath := filepath.Join(exportDirPath, "exportOptions.plist")
+	if err := ioutil.WriteFile(exportOptionsPath, []byte(cmd.exportOptionsPlist()), 0644); err != nil {
+		return "", "", fmt.Errorf("failed to write exportOptions.plist: %s", err)
+	}
+
+	out, err := cmd.runXcodebuild("-exportArchive",
+		"-archivePath", archivePath,
+		"-exportPath", exportDirPath,
+		"-exportOptionsPlist", exportOptionsPath)
+	return exportDirPath, out, err
+}
+
+func (cmd CommandModel) exportOptionsPlist() string {
+	teamIDEntry := ""
+	if cmd.TeamID != "" {
+		teamIDEntry = fmt.Sprintf("\t<key>teamID</key>\n\t<string>%s</string>\n", cmd.TeamID)
+	}
+
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>method</key>
+	<string>%s</string>
+%s	<key>signingStyle</key>
+	<string>automatic</string>
+</dict>
+</plist>
+`, cmd.ExportMethod, teamIDEntry)
+}
+
+func (cmd CommandModel) runXcodebuild(args ...string) (string, error) {
+	log.Debugf("$ xcodebuild %s", strings.Join(args, " "))
+	out, err := cmdex.RunCommandAndReturnCombinedStdoutAndStderr("xcodebuild", args...)
+	return out, err
+}
+
+// collectProvisioningProfilesFromAppDir parses the embedded.mobileprovision
+// file inside every .app found directly under searchPath - the shape of an
+// .xcarchive's Products/Applications directory.
+func collectProvisioningProfilesFromAppDir(searchPath string) ([]provprofile.ProvisioningProfileFileInfoModel, error) {
+	appPaths, err := filepath.Glob(filepath.Join(searchPath, "*.app"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to search for .app bundles in %s: %s", searchPath, err)
+	}
+
+	var provProfiles []provprofile.ProvisioningProfileFileInfoModel
+	for _, appPath := range appPaths {
+		embeddedPath := filepath.Join(appPath, "embedded.mobileprovision")
+		if _, err := os.Stat(embeddedPath); err != nil {
+			continue
+		}
+
+		provProfileInfo, err := provprofile.NewProvisioningProfileFileInfoModelFromFile(embeddedPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse Provisioning Profile embedded in %s: %s", appPath, err)
+		}
+		provProfiles = append(provProfiles, provProfileInfo)
+	}
+
+	return provProfiles, nil
+}
+
+// collectProvisioningProfilesFromExportDir parses the embedded.mobileprovision
+// file inside the .app(s) packaged into the .ipa that `xcodebuild
+// -exportArchive` writes into exportDirPath. Unlike an .xcarchive, an
+// exported .ipa is a zip archive (with its .app(s) under Payload/), so the
+// Provisioning Profile has to be read out of the zip instead of off disk.
+func collectProvisioningProfilesFromExportDir(exportDirPath string) ([]provprofile.ProvisioningProfileFileInfoModel, error) {
+	ipaPaths, err := filepath.Glob(filepath.Join(exportDirPath, "*.ipa"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to search for .ipa files in %s: %s", exportDirPath, err)
+	}
+
+	var provProfiles []provprofile.ProvisioningProfileFileInfoModel
+	for _, ipaPath := range ipaPaths {
+		ipaProfiles, err := collectProvisioningProfilesFromIPA(ipaPath)
+		if err != nil {
+			return nil, err
+		}
+		provProfiles = append(provProfiles, ipaProfiles...)
+	}
+
+	return provProfiles, nil
+}
+
+func collectProvisioningProfilesFromIPA(ipaPath string) ([]provprofile.ProvisioningProfileFileInfoModel, error) {
+	zipReader, err := zip.OpenReader(ipaPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open IPA (%s) as a zip archive: %s", ipaPath, err)
+	}
+	defer func() {
+		if err := zipReader.Close(); err != nil {
+			log.Warnf("Failed to close IPA (%s): %s", ipaPath, err)
+		}
+	}()
+
+	var provProfiles []provprofile.ProvisioningProfileFileInfoModel
+	for _, zipFile := range zipReader.File {
+		if !strings.HasSuffix(zipFile.Name, ".app/embedded.mobileprovision") {
+			continue
+		}
+
+		provProfileInfo, err := readProvisioningProfileFromZipEntry(zipFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Provisioning Profile (%s) from IPA (%s): %s", zipFile.Name, ipaPath, err)
+		}
+		provProfiles = append(provProfiles, provProfileInfo)
+	}
+
+	return provProfiles, nil
+}
+
+// readProvisioningProfileFromZipEntry extracts embedded.mobileprovision from
+// inside an .ipa's zip entry, writes it to a temp file (so it can later be
+// `cp`-ed like any other Provisioning Profile found on disk) and parses it.
+func readProvisioningProfileFromZipEntry(zipFile *zip.File) (provprofile.ProvisioningProfileFileInfoModel, error) {
+	rc, err := zipFile.Open()
+	if err != nil {
+		return provprofile.ProvisioningProfileFileInfoModel{}, err
+	}
+	defer func() {
+		if err := rc.Close(); err != nil {
+			log.Warnf("Failed to close zip entry %s: %s", zipFile.Name, err)
+		}
+	}()
+
+	content, err := ioutil.ReadAll(rc)
+	if err != nil {
+		return provprofile.ProvisioningProfileFileInfoModel{}, err
+	}
+
+	info, err := provprofile.ParseProvisioningProfileContent(content)
+	if err != nil {
+		return provprofile.ProvisioningProfileFileInfoModel{}, err
+	}
+
+	extractedPath, err := writeTempFile("codesigndoc-embedded-profile", "embedded.mobileprovision", content)
+	if err != nil {
+		return provprofile.ProvisioningProfileFileInfoModel{}, err
+	}
+
+	return provprofile.ProvisioningProfileFileInfoModel{
+		Path:                    extractedPath,
+		ProvisioningProfileInfo: info,
+	}, nil
+}
+
+func writeTempFile(tempDirPrefix, fileName string, content []byte) (string, error) {
+	tmpDirPath, err := pathutil.NormalizedOSTempDirPath(tempDirPrefix)
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp dir (%s): %s", tempDirPrefix, err)
+	}
+
+	tmpFilePath := filepath.Join(tmpDirPath, fileName)
+	if err := ioutil.WriteFile(tmpFilePath, content, 0644); err != nil {
+		return "", fmt.Errorf("failed to write temp file (%s): %s", tmpFilePath, err)
+	}
+	return tmpFilePath, nil
+}
+
+// discoverRequiredCertificates matches every Developer Certificate
+// referenced by provProfiles against the identities installed in the login
+// Keychain, and exports the ones actually required to a temporary .p12 file
+// so they can be copied into the export dir like any other asset.
+func discoverRequiredCertificates(provProfiles []provprofile.ProvisioningProfileFileInfoModel, p12Password string) ([]certificateutil.CertificateInfoModel, error) {
+	requiredFingerprints := map[string]bool{}
+	for _, profile := range provProfiles {
+		for _, cert := range profile.ProvisioningProfileInfo.DeveloperCertificates {
+			requiredFingerprints[cert.SHA1Fingerprint] = true
+		}
+	}
+	if len(requiredFingerprints) == 0 {
+		return nil, nil
+	}
+
+	installedCertInfos, err := certificateutil.InstalledCertificateInfos()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list installed Keychain certificates: %s", err)
+	}
+
+	var exportedCertInfos []certificateutil.CertificateInfoModel
+	for _, certInfo := range installedCertInfos {
+		if !requiredFingerprints[certInfo.SHA1Fingerprint] {
+			continue
+		}
+
+		exportedCertInfo, err := certInfo.ExportWithPrivateKey(p12Password)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export Certificate %s: %s", certInfo.CommonName, err)
+		}
+		exportedCertInfos = append(exportedCertInfos, exportedCertInfo)
+	}
+
+	return exportedCertInfos, nil
+}
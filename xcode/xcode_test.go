@@ -0,0 +1,80 @@
+package xcode
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestArchiveArgs(t *testing.T) {
+	cmd := CommandModel{
+		ProjectFilePath: "/path/to/Project.xcworkspace",
+		Scheme:          "MyScheme",
+		Configuration:   "Release",
+		TeamID:          "ABCDE12345",
+		XcodeBuildArgs:  []string{"ONLY_ACTIVE_ARCH=NO"},
+	}
+
+	args := cmd.archiveArgs("/tmp/MyScheme.xcarchive")
+
+	want := []string{
+		"-workspace", "/path/to/Project.xcworkspace",
+		"archive", "-scheme", "MyScheme", "-archivePath", "/tmp/MyScheme.xcarchive",
+		"-configuration", "Release",
+		"-destination", defaultDestination,
+		"DEVELOPMENT_TEAM=ABCDE12345",
+		"ONLY_ACTIVE_ARCH=NO",
+	}
+	if len(args) != len(want) {
+		t.Fatalf("archiveArgs() = %v, want %v", args, want)
+	}
+	for i := range args {
+		if args[i] != want[i] {
+			t.Errorf("archiveArgs()[%d] = %q, want %q", i, args[i], want[i])
+		}
+	}
+}
+
+func TestArchiveArgs_ProjectFile(t *testing.T) {
+	cmd := CommandModel{ProjectFilePath: "/path/to/Project.xcodeproj", Scheme: "MyScheme"}
+
+	args := cmd.archiveArgs("/tmp/MyScheme.xcarchive")
+	if args[0] != "-project" || args[1] != "/path/to/Project.xcodeproj" {
+		t.Errorf("archiveArgs() = %v, want it to start with -project for a .xcodeproj", args)
+	}
+}
+
+func TestArchiveArgs_CustomDestination(t *testing.T) {
+	cmd := CommandModel{ProjectFilePath: "/path/to/Project.xcodeproj", Scheme: "MyScheme", Destination: "generic/platform=iOS Simulator"}
+
+	args := cmd.archiveArgs("/tmp/MyScheme.xcarchive")
+	for i, arg := range args {
+		if arg == "-destination" {
+			if i+1 >= len(args) || args[i+1] != cmd.Destination {
+				t.Errorf("expected -destination to be followed by %q, got %v", cmd.Destination, args)
+			}
+			return
+		}
+	}
+	t.Error("-destination not found in archiveArgs() output")
+}
+
+func TestExportOptionsPlist(t *testing.T) {
+	cmd := CommandModel{ExportMethod: "app-store"}
+	plist := cmd.exportOptionsPlist()
+
+	if !strings.Contains(plist, "<string>app-store</string>") {
+		t.Errorf("exportOptionsPlist() = %q, want it to contain the export method", plist)
+	}
+	if strings.Contains(plist, "<key>teamID</key>") {
+		t.Errorf("exportOptionsPlist() = %q, want no teamID entry when TeamID is empty", plist)
+	}
+}
+
+func TestExportOptionsPlist_WithTeamID(t *testing.T) {
+	cmd := CommandModel{ExportMethod: "ad-hoc", TeamID: "ABCDE12345"}
+	plist := cmd.exportOptionsPlist()
+
+	if !strings.Contains(plist, "<key>teamID</key>") || !strings.Contains(plist, "<string>ABCDE12345</string>") {
+		t.Errorf("exportOptionsPlist() = %q, want a teamID entry for %q", plist, cmd.TeamID)
+	}
+}